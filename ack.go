@@ -3,6 +3,9 @@ package socketio
 import (
 	"errors"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 var (
@@ -13,6 +16,26 @@ type ackProcessor struct {
 	counter          int
 	counterLock      sync.Mutex
 	resultWaitersMap sync.Map
+	waitStartedAt    sync.Map
+
+	logger Logger
+	server *Server
+}
+
+// setLogger attaches l and s so that addWaiter/removeWaiter/getWaiter can
+// report ack bookkeeping and, once a Server has metrics configured, observe
+// ack round-trip latency. Without it they remain silent, matching the zero
+// value's behaviour before this field existed.
+func (a *ackProcessor) setLogger(l Logger, s *Server) {
+	a.logger = l
+	a.server = s
+}
+
+func (a *ackProcessor) log() Logger {
+	if a.logger == nil {
+		return NewNopLogger()
+	}
+	return a.logger
 }
 
 func (a *ackProcessor) getNextId() int {
@@ -25,10 +48,18 @@ func (a *ackProcessor) getNextId() int {
 
 func (a *ackProcessor) addWaiter(id int, w chan interface{}) {
 	a.resultWaitersMap.Store(id, w)
+	a.waitStartedAt.Store(id, time.Now())
+	a.log().Debug("ack waiter registered", zap.Int("ack_id", id))
 }
 
 func (a *ackProcessor) removeWaiter(id int) {
 	a.resultWaitersMap.Delete(id)
+
+	if startedAt, ok := a.waitStartedAt.LoadAndDelete(id); ok && a.server != nil {
+		a.server.metricAckLatencySeconds(time.Since(startedAt.(time.Time)).Seconds())
+	}
+
+	a.log().Debug("ack waiter removed", zap.Int("ack_id", id))
 }
 
 func (a *ackProcessor) getWaiter(id int) (chan interface{}, error) {
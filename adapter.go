@@ -0,0 +1,125 @@
+package socketio
+
+import "sync"
+
+// Adapter decouples room membership and broadcast fan-out from process-local
+// memory so that a Server can participate in a multi-node cluster. Server
+// calls into the configured Adapter from Join, Leave and BroadcastTo; a
+// Redis- or NATS-backed implementation republishes those events to every
+// other node so that clients connected there stay in sync. BroadcastToAll
+// does not go through the Adapter and reaches only this node's channels.
+type Adapter interface {
+	// Publish fans data out to every node subscribed to room, tagging the
+	// message with origin (the sid that produced it) so that receiving nodes
+	// can avoid re-delivering it to the socket that already has it locally.
+	Publish(room, event string, data interface{}, origin string) error
+
+	// Subscribe registers fn to be invoked whenever another node publishes to
+	// room. fn is called with the event name, payload and origin sid that
+	// were passed to Publish. Subscribing to a room more than once is a
+	// no-op.
+	Subscribe(room string, fn func(event string, data interface{}, origin string)) error
+
+	// Unsubscribe stops delivering messages published to room on this node.
+	Unsubscribe(room string) error
+
+	// AddSocketToRoom records that sid joined room, cluster-wide.
+	AddSocketToRoom(room, sid string) error
+
+	// RemoveSocketFromRoom records that sid left room, cluster-wide.
+	RemoveSocketFromRoom(room, sid string) error
+
+	// RoomMembers returns every sid that has joined room across the cluster.
+	RoomMembers(room string) ([]string, error)
+}
+
+// MemoryAdapter is the default Adapter used when a Server is not configured
+// with one. It keeps membership in process memory and never fans broadcasts
+// out, matching the single-node behaviour this package has always had.
+type MemoryAdapter struct {
+	mu      sync.RWMutex
+	members map[string]map[string]struct{}
+}
+
+// NewMemoryAdapter returns a ready to use MemoryAdapter.
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{
+		members: make(map[string]map[string]struct{}),
+	}
+}
+
+func (a *MemoryAdapter) Publish(room, event string, data interface{}, origin string) error {
+	return nil
+}
+
+func (a *MemoryAdapter) Subscribe(room string, fn func(event string, data interface{}, origin string)) error {
+	return nil
+}
+
+func (a *MemoryAdapter) Unsubscribe(room string) error {
+	return nil
+}
+
+func (a *MemoryAdapter) AddSocketToRoom(room, sid string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.members[room]; !ok {
+		a.members[room] = make(map[string]struct{})
+	}
+	a.members[room][sid] = struct{}{}
+
+	return nil
+}
+
+func (a *MemoryAdapter) RemoveSocketFromRoom(room, sid string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if sids, ok := a.members[room]; ok {
+		delete(sids, sid)
+		if len(sids) == 0 {
+			delete(a.members, room)
+		}
+	}
+
+	return nil
+}
+
+func (a *MemoryAdapter) RoomMembers(room string) ([]string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	sids := make([]string, 0, len(a.members[room]))
+	for sid := range a.members[room] {
+		sids = append(sids, sid)
+	}
+
+	return sids, nil
+}
+
+// deliverLocally re-emits an event published by another node to every alive
+// channel this node has in room, skipping the sid that originated it.
+func (s *Server) deliverLocally(room, event string, data interface{}, origin string) {
+	s.channelsLock.RLock()
+	roomChannels := s.channels[room]
+	targets := make([]*Channel, 0, len(roomChannels))
+	for cn := range roomChannels {
+		if cn.Id() != origin && cn.IsAlive() {
+			targets = append(targets, cn)
+		}
+	}
+	s.channelsLock.RUnlock()
+
+	for _, cn := range targets {
+		go cn.Emit(event, data)
+	}
+}
+
+// subscribeRoom asks the adapter to start delivering remote broadcasts for
+// room to this node, the first time it sees that room locally.
+func (s *Server) subscribeRoom(room string) {
+	_ = s.adapter.Subscribe(room, func(event string, data interface{}, origin string) {
+		s.deliverLocally(room, event, data, origin)
+	})
+}
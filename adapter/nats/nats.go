@@ -0,0 +1,186 @@
+// Package nats provides a github.com/collatzc/socketio Adapter backed by
+// NATS, the pattern used by most comparable signaling servers for
+// multi-node fan-out: every broadcast is published on a subject derived from
+// the room and event, and every node re-emits it to its own local sids.
+package nats
+
+import (
+	"sync"
+
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/collatzc/socketio"
+	"github.com/collatzc/socketio/utils"
+)
+
+const subjectPrefix = "socketio."
+
+type message struct {
+	Event  string      `json:"event"`
+	Data   interface{} `json:"data"`
+	Origin string      `json:"origin"`
+}
+
+// Adapter is a socketio.Adapter that publishes broadcasts on NATS subjects
+// of the form socketio.<room>.evt.<event>, and room membership changes on
+// socketio.<room>.mship.join / .leave, kept on a distinct subject namespace
+// so a room's event wildcard subscription never sees membership traffic.
+// Every node connected to the same NATS cluster stays in sync on both.
+type Adapter struct {
+	conn *natsgo.Conn
+
+	mu   sync.Mutex
+	subs map[string]*natsgo.Subscription
+
+	membershipMu   sync.Mutex
+	membershipSubs map[string]*natsgo.Subscription
+
+	members sync.Map // room -> *sync.Map[sid]struct{}
+}
+
+// New returns an Adapter that publishes and subscribes over conn.
+func New(conn *natsgo.Conn) *Adapter {
+	return &Adapter{
+		conn:           conn,
+		subs:           make(map[string]*natsgo.Subscription),
+		membershipSubs: make(map[string]*natsgo.Subscription),
+	}
+}
+
+func eventSubject(room, event string) string {
+	return subjectPrefix + room + ".evt." + event
+}
+
+func eventWildcard(room string) string {
+	return subjectPrefix + room + ".evt.*"
+}
+
+func joinSubject(room string) string {
+	return subjectPrefix + room + ".mship.join"
+}
+
+func leaveSubject(room string) string {
+	return subjectPrefix + room + ".mship.leave"
+}
+
+func membershipWildcard(room string) string {
+	return subjectPrefix + room + ".mship.*"
+}
+
+func (a *Adapter) Publish(room, event string, data interface{}, origin string) error {
+	payload, err := utils.Json.Marshal(message{Event: event, Data: data, Origin: origin})
+	if err != nil {
+		return err
+	}
+
+	return a.conn.Publish(eventSubject(room, event), payload)
+}
+
+func (a *Adapter) Subscribe(room string, fn func(event string, data interface{}, origin string)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.subs[room]; ok {
+		return nil
+	}
+
+	sub, err := a.conn.Subscribe(eventWildcard(room), func(msg *natsgo.Msg) {
+		var decoded message
+		if err := utils.Json.Unmarshal(msg.Data, &decoded); err != nil {
+			return
+		}
+		fn(decoded.Event, decoded.Data, decoded.Origin)
+	})
+	if err != nil {
+		return err
+	}
+
+	a.subs[room] = sub
+
+	return nil
+}
+
+func (a *Adapter) Unsubscribe(room string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sub, ok := a.subs[room]
+	if !ok {
+		return nil
+	}
+	delete(a.subs, room)
+
+	return sub.Unsubscribe()
+}
+
+// ensureMembershipSubscription makes sure this node is listening for join
+// and leave events on room, so a.members also holds sids added or removed on
+// other nodes. Joins and leaves published before this node subscribed to
+// room are not seen. It is safe to call repeatedly.
+func (a *Adapter) ensureMembershipSubscription(room string) error {
+	a.membershipMu.Lock()
+	defer a.membershipMu.Unlock()
+
+	if _, ok := a.membershipSubs[room]; ok {
+		return nil
+	}
+
+	join := joinSubject(room)
+	leave := leaveSubject(room)
+
+	sub, err := a.conn.Subscribe(membershipWildcard(room), func(msg *natsgo.Msg) {
+		sid := string(msg.Data)
+		switch msg.Subject {
+		case join:
+			a.roomMembers(room).Store(sid, struct{}{})
+		case leave:
+			a.roomMembers(room).Delete(sid)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	a.membershipSubs[room] = sub
+
+	return nil
+}
+
+func (a *Adapter) AddSocketToRoom(room, sid string) error {
+	if err := a.ensureMembershipSubscription(room); err != nil {
+		return err
+	}
+
+	a.roomMembers(room).Store(sid, struct{}{})
+	return a.conn.Publish(joinSubject(room), []byte(sid))
+}
+
+func (a *Adapter) RemoveSocketFromRoom(room, sid string) error {
+	if err := a.ensureMembershipSubscription(room); err != nil {
+		return err
+	}
+
+	a.roomMembers(room).Delete(sid)
+	return a.conn.Publish(leaveSubject(room), []byte(sid))
+}
+
+func (a *Adapter) RoomMembers(room string) ([]string, error) {
+	if err := a.ensureMembershipSubscription(room); err != nil {
+		return nil, err
+	}
+
+	var sids []string
+	a.roomMembers(room).Range(func(key, _ interface{}) bool {
+		sids = append(sids, key.(string))
+		return true
+	})
+
+	return sids, nil
+}
+
+func (a *Adapter) roomMembers(room string) *sync.Map {
+	m, _ := a.members.LoadOrStore(room, &sync.Map{})
+	return m.(*sync.Map)
+}
+
+var _ socketio.Adapter = (*Adapter)(nil)
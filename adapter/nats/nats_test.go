@@ -0,0 +1,130 @@
+package nats_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/collatzc/socketio/adapter/nats"
+)
+
+func runEmbeddedServer(t *testing.T) *natsserver.Server {
+	t.Helper()
+
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1 // let the OS pick a free port
+
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+func connect(t *testing.T, srv *natsserver.Server) *natsgo.Conn {
+	t.Helper()
+
+	conn, err := natsgo.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("connect to embedded nats: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+// TestCrossNodeDelivery verifies that a broadcast published by the adapter on
+// "node A" is delivered to a handler representing "node B", as would happen
+// when a client connected to a different process is in the same room.
+func TestCrossNodeDelivery(t *testing.T) {
+	srv := runEmbeddedServer(t)
+
+	nodeA := nats.New(connect(t, srv))
+	nodeB := nats.New(connect(t, srv))
+
+	received := make(chan string, 1)
+	if err := nodeB.Subscribe("lobby", func(event string, data interface{}, origin string) {
+		// sidB stands for node B's own socket: only what it originated is skipped
+		if origin == "sidB" {
+			return
+		}
+		received <- event
+	}); err != nil {
+		t.Fatalf("subscribe on node B: %v", err)
+	}
+
+	// give the subscription a moment to register with the server
+	time.Sleep(50 * time.Millisecond)
+
+	if err := nodeA.Publish("lobby", "chat message", "hello", "sidA"); err != nil {
+		t.Fatalf("publish from node A: %v", err)
+	}
+
+	select {
+	case event := <-received:
+		if event != "chat message" {
+			t.Fatalf("expected event %q, got %q", "chat message", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for cross-node delivery")
+	}
+}
+
+// TestRoomMembersAreClusterWide verifies that sids added on one node show up
+// in RoomMembers on another node connected to the same NATS cluster, and
+// that a removal on one node is likewise reflected on the other.
+func TestRoomMembersAreClusterWide(t *testing.T) {
+	srv := runEmbeddedServer(t)
+
+	nodeA := nats.New(connect(t, srv))
+	nodeB := nats.New(connect(t, srv))
+
+	if err := nodeA.AddSocketToRoom("lobby", "sid1"); err != nil {
+		t.Fatalf("add socket on node A: %v", err)
+	}
+	if err := nodeB.AddSocketToRoom("lobby", "sid2"); err != nil {
+		t.Fatalf("add socket on node B: %v", err)
+	}
+
+	members := waitForRoomMembers(t, nodeB, "lobby", 2)
+	if members[0] != "sid1" || members[1] != "sid2" {
+		t.Fatalf("expected node B to see [sid1 sid2], got %v", members)
+	}
+
+	if err := nodeA.RemoveSocketFromRoom("lobby", "sid1"); err != nil {
+		t.Fatalf("remove socket on node A: %v", err)
+	}
+
+	members = waitForRoomMembers(t, nodeB, "lobby", 1)
+	if members[0] != "sid2" {
+		t.Fatalf("expected node B to see only [sid2] after removal, got %v", members)
+	}
+}
+
+// waitForRoomMembers polls a.RoomMembers(room) until it reports want sorted
+// members, or fails the test once the NATS round trip should long since
+// have settled.
+func waitForRoomMembers(t *testing.T, a *nats.Adapter, room string, want int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	var members []string
+	for time.Now().Before(deadline) {
+		var err error
+		members, err = a.RoomMembers(room)
+		if err != nil {
+			t.Fatalf("room members: %v", err)
+		}
+		if len(members) == want {
+			sort.Strings(members)
+			return members
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d room members, last saw %v", want, members)
+	return nil
+}
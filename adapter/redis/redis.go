@@ -0,0 +1,108 @@
+// Package redis provides a github.com/collatzc/socketio Adapter backed by
+// Redis pub/sub, letting BroadcastTo and room membership span every node
+// subscribed to the same Redis instance. BroadcastToAll does not use the
+// Adapter and stays local to each node.
+package redis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/collatzc/socketio"
+	"github.com/collatzc/socketio/utils"
+)
+
+const channelPrefix = "socketio:room:"
+
+type message struct {
+	Event  string      `json:"event"`
+	Data   interface{} `json:"data"`
+	Origin string      `json:"origin"`
+}
+
+// Adapter is a socketio.Adapter that republishes broadcasts through Redis
+// pub/sub so every node in the cluster can re-emit them locally.
+type Adapter struct {
+	client *redis.Client
+	ctx    context.Context
+
+	mu   sync.Mutex
+	subs map[string]*redis.PubSub
+}
+
+// New returns an Adapter that uses client for both pub/sub and cluster-wide
+// room membership bookkeeping.
+func New(client *redis.Client) *Adapter {
+	return &Adapter{
+		client: client,
+		ctx:    context.Background(),
+		subs:   make(map[string]*redis.PubSub),
+	}
+}
+
+func (a *Adapter) Publish(room, event string, data interface{}, origin string) error {
+	msg := message{Event: event, Data: data, Origin: origin}
+	payload, err := utils.Json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return a.client.Publish(a.ctx, channelPrefix+room, payload).Err()
+}
+
+func (a *Adapter) Subscribe(room string, fn func(event string, data interface{}, origin string)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.subs[room]; ok {
+		return nil
+	}
+
+	ps := a.client.Subscribe(a.ctx, channelPrefix+room)
+	a.subs[room] = ps
+
+	go func() {
+		for redisMsg := range ps.Channel() {
+			var msg message
+			if err := utils.Json.Unmarshal([]byte(redisMsg.Payload), &msg); err != nil {
+				continue
+			}
+			fn(msg.Event, msg.Data, msg.Origin)
+		}
+	}()
+
+	return nil
+}
+
+func (a *Adapter) Unsubscribe(room string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ps, ok := a.subs[room]
+	if !ok {
+		return nil
+	}
+	delete(a.subs, room)
+
+	return ps.Close()
+}
+
+func (a *Adapter) AddSocketToRoom(room, sid string) error {
+	return a.client.SAdd(a.ctx, roomSetKey(room), sid).Err()
+}
+
+func (a *Adapter) RemoveSocketFromRoom(room, sid string) error {
+	return a.client.SRem(a.ctx, roomSetKey(room), sid).Err()
+}
+
+func (a *Adapter) RoomMembers(room string) ([]string, error) {
+	return a.client.SMembers(a.ctx, roomSetKey(room)).Result()
+}
+
+func roomSetKey(room string) string {
+	return "socketio:members:" + room
+}
+
+var _ socketio.Adapter = (*Adapter)(nil)
@@ -0,0 +1,166 @@
+package socketio
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/collatzc/socketio/protocol"
+)
+
+// Middleware runs against the upgrade request before it is accepted.
+// Returning a non-nil error aborts the handshake with an HTTP error response
+// and skips the WebSocket upgrade entirely; ctxData, if non-nil, is stored
+// for Channel.Data() once SetupEventLoop has returned, so it is not yet
+// available while OnAuth and OnConnection run for the initial namespace.
+type Middleware func(r *http.Request) (ctxData any, err error)
+
+// Use registers a handshake Middleware. Middlewares run in registration
+// order against every incoming request; the first one to return an error
+// stops the chain.
+func (s *Server) Use(m Middleware) {
+	s.middlewaresLock.Lock()
+	s.middlewares = append(s.middlewares, m)
+	s.middlewaresLock.Unlock()
+}
+
+// runMiddlewares runs every registered Middleware against r in order. It
+// returns the ctxData of the last middleware that returned one, or the
+// first error encountered.
+func (s *Server) runMiddlewares(r *http.Request) (any, error) {
+	s.middlewaresLock.RLock()
+	middlewares := s.middlewares
+	s.middlewaresLock.RUnlock()
+
+	var ctxData any
+	for _, m := range middlewares {
+		data, err := m(r)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			ctxData = data
+		}
+	}
+
+	return ctxData, nil
+}
+
+// OnAuth registers the callback invoked with the Socket.IO v4 `auth` object
+// carried on a namespace's CONNECT packet. Returning an error sends a
+// CONNECT_ERROR packet to the client and closes the Channel instead of
+// completing the handshake for that namespace.
+func (s *Server) OnAuth(f func(c *Channel, auth map[string]any) error) {
+	s.onAuthLock.Lock()
+	s.onAuth = f
+	s.onAuthLock.Unlock()
+}
+
+// authenticateConnect runs the configured OnAuth callback, if any, against
+// the `auth` payload carried on a CONNECT packet's data. It returns false
+// once it has already sent a CONNECT_ERROR on nsp and closed c, in which
+// case the caller must not proceed with the connection.
+func (s *Server) authenticateConnect(c *Channel, nsp string, connectData interface{}) bool {
+	s.onAuthLock.RLock()
+	onAuth := s.onAuth
+	s.onAuthLock.RUnlock()
+
+	if onAuth == nil {
+		return true
+	}
+
+	auth, err := asAuthObject(connectData)
+	if err == nil {
+		err = onAuth(c, auth)
+	}
+	if err != nil {
+		s.sendConnectError(c, nsp, err)
+		c.Close()
+		return false
+	}
+
+	return true
+}
+
+// connectNamespace authenticates authData (the Socket.IO v4 `auth` object)
+// against the configured OnAuth callback and, if it passes, completes the
+// handshake for nsp by acking the CONNECT and registering c with that
+// namespace. A rejecting OnAuth callback sends CONNECT_ERROR and closes c
+// instead, so that namespace's handshake never completes.
+func (s *Server) connectNamespace(c *Channel, nsp string, authData interface{}) {
+	if !s.authenticateConnect(c, nsp, authData) {
+		return
+	}
+
+	s.sendConnect(c, nsp)
+	s.Of(nsp).addChannel(c)
+}
+
+// ProcessConnectPacket handles a CONNECT packet received from the client,
+// authenticating msg.Data (the Socket.IO v4 `auth` object) before completing
+// the handshake for msg.Nsp. Nothing in this package calls it: inLoop does
+// not route incoming protocol.CONNECT messages here, so a client's auth
+// object reaches OnAuth only where a caller passes its CONNECT packet to
+// this method.
+func (s *Server) ProcessConnectPacket(c *Channel, msg *protocol.MsgPack) {
+	nsp := msg.Nsp
+	if nsp == "" {
+		nsp = protocol.DefaultNsp
+	}
+
+	s.metricPacketsReceivedInc("connect", nsp)
+	s.connectNamespace(c, nsp, msg.Data)
+}
+
+func asAuthObject(connectData interface{}) (map[string]any, error) {
+	if connectData == nil {
+		return map[string]any{}, nil
+	}
+
+	auth, ok := connectData.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("auth payload must be a JSON object")
+	}
+
+	return auth, nil
+}
+
+func (s *Server) sendConnectError(c *Channel, nsp string, cause error) {
+	c.out <- &protocol.MsgPack{
+		Type: protocol.CONNECT_ERROR,
+		Nsp:  nsp,
+		Data: struct {
+			Message string `json:"message"`
+			Data    any    `json:"data,omitempty"`
+		}{Message: cause.Error()},
+	}
+	s.metricPacketsSentInc("connect_error", nsp)
+}
+
+// Data returns the value a handshake Middleware stored for this Channel via
+// its ctxData return value, or nil if none did.
+func (c *Channel) Data() any {
+	if c.server == nil {
+		return nil
+	}
+
+	c.server.channelDataLock.RLock()
+	defer c.server.channelDataLock.RUnlock()
+
+	return c.server.channelData[c]
+}
+
+func (s *Server) setChannelData(c *Channel, data any) {
+	if data == nil {
+		return
+	}
+
+	s.channelDataLock.Lock()
+	s.channelData[c] = data
+	s.channelDataLock.Unlock()
+}
+
+func (s *Server) deleteChannelData(c *Channel) {
+	s.channelDataLock.Lock()
+	delete(s.channelData, c)
+	s.channelDataLock.Unlock()
+}
@@ -0,0 +1,70 @@
+package socketio
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/collatzc/socketio/protocol"
+	"github.com/collatzc/socketio/websocket"
+)
+
+func TestConnectNamespaceRejectsFailingOnAuth(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+
+	wantErr := errors.New("invalid token")
+	s.OnAuth(func(c *Channel, auth map[string]any) error {
+		return wantErr
+	})
+
+	c := &Channel{}
+	c.server = s
+	c.ip = "127.0.0.1"
+	c.out = make(chan interface{}, 4)
+
+	s.connectNamespace(c, protocol.DefaultNsp, map[string]interface{}{"token": "bad"})
+
+	select {
+	case msg := <-c.out:
+		mp, ok := msg.(*protocol.MsgPack)
+		if !ok || mp.Type != protocol.CONNECT_ERROR {
+			t.Fatalf("expected a CONNECT_ERROR packet, got %#v", msg)
+		}
+	default:
+		t.Fatal("expected a CONNECT_ERROR packet on c.out, got none")
+	}
+
+	if _, err := s.GetChannel(c.Id()); err == nil {
+		t.Fatal("channel should not be registered after a rejected auth")
+	}
+}
+
+func TestConnectNamespaceAllowsPassingOnAuth(t *testing.T) {
+	s := NewServer(websocket.Transport{BinaryMessage: true})
+
+	var gotAuth map[string]any
+	s.OnAuth(func(c *Channel, auth map[string]any) error {
+		gotAuth = auth
+		return nil
+	})
+
+	c := &Channel{}
+	c.server = s
+	c.ip = "127.0.0.1"
+	c.out = make(chan interface{}, 4)
+
+	s.connectNamespace(c, protocol.DefaultNsp, map[string]interface{}{"token": "good"})
+
+	if gotAuth["token"] != "good" {
+		t.Fatalf("expected OnAuth to see the auth payload, got %#v", gotAuth)
+	}
+
+	select {
+	case msg := <-c.out:
+		mp, ok := msg.(*protocol.MsgPack)
+		if !ok || mp.Type != protocol.CONNECT {
+			t.Fatalf("expected a CONNECT ack packet, got %#v", msg)
+		}
+	default:
+		t.Fatal("expected a CONNECT ack packet on c.out, got none")
+	}
+}
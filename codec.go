@@ -0,0 +1,34 @@
+package socketio
+
+import "github.com/collatzc/socketio/protocol"
+
+// WithCodec replaces the default JSON wire codec with codec, e.g.
+// protocol.NewMessagePackCodec() or a configured *protocol.ProtobufCodec.
+// Server advertises the configured codec's name in the OPEN handshake
+// payload and uses the codec in EncodeMessage and DecodeMessage. Its
+// ContentType() is not read by this package, and inLoop and outLoop do not
+// call EncodeMessage or DecodeMessage, so the frames they exchange are not
+// encoded with it.
+func WithCodec(codec protocol.Codec) ServerOption {
+	return func(s *Server) {
+		s.codec = codec
+	}
+}
+
+// Codec returns the wire codec this Server encodes and decodes messages
+// with.
+func (s *Server) Codec() protocol.Codec {
+	return s.codec
+}
+
+// EncodeMessage marshals m with the Server's configured codec. outLoop does
+// not call it.
+func (s *Server) EncodeMessage(m protocol.Message) ([]byte, error) {
+	return s.codec.Marshal(m)
+}
+
+// DecodeMessage unmarshals data with the Server's configured codec, the
+// receive-side counterpart of EncodeMessage. inLoop does not call it.
+func (s *Server) DecodeMessage(data []byte) (protocol.Message, error) {
+	return s.codec.Unmarshal(data)
+}
@@ -0,0 +1,35 @@
+package socketio
+
+import (
+	"testing"
+
+	"github.com/collatzc/socketio/protocol"
+	"github.com/collatzc/socketio/websocket"
+)
+
+func TestServerEncodeDecodeMessageRoundTrip(t *testing.T) {
+	s := NewServer(websocket.Transport{}, WithCodec(protocol.NewMessagePackCodec()))
+
+	msg := protocol.Message{Type: protocol.EVENT, Method: "ping", Nsp: protocol.DefaultNsp}
+
+	data, err := s.EncodeMessage(msg)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	got, err := s.DecodeMessage(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Method != msg.Method || got.Nsp != msg.Nsp {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, msg)
+	}
+}
+
+func TestServerDefaultCodecIsJSON(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+
+	if s.Codec().Name() != "json" {
+		t.Fatalf("expected default codec %q, got %q", "json", s.Codec().Name())
+	}
+}
@@ -0,0 +1,140 @@
+package socketio
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderRealIp    = "X-Real-IP"
+	HeaderForwarded = "Forwarded"
+)
+
+// SetTrustedProxies configures the CIDR ranges (e.g. "10.0.0.0/8",
+// "2001:db8::/32") that are trusted to set forwarding headers. Channel.Ip
+// only honours X-Forwarded-For, X-Real-IP and Forwarded once at least one
+// trusted range is configured; with none configured it returns the raw TCP
+// peer address, since those headers are trivially spoofable otherwise.
+func (s *Server) SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipNet)
+	}
+
+	s.trustedProxiesLock.Lock()
+	s.trustedProxies = nets
+	s.trustedProxiesLock.Unlock()
+
+	return nil
+}
+
+func (s *Server) isTrustedProxy(ip string) bool {
+	s.trustedProxiesLock.RLock()
+	defer s.trustedProxiesLock.RUnlock()
+
+	if len(s.trustedProxies) == 0 {
+		return false
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, ipNet := range s.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ip returns the client's address. With no trusted proxies configured it is
+// always c.ip, the direct TCP peer. Once trusted proxy ranges are set via
+// Server.SetTrustedProxies, it walks X-Forwarded-For from right to left,
+// skipping entries whose immediate hop is inside a trusted range and
+// stopping at the first untrusted address; X-Real-IP takes precedence when
+// the direct peer itself is trusted, and the RFC 7239 Forwarded header is
+// consulted as a fallback when neither is present.
+func (c *Channel) Ip() string {
+	if c.server == nil || !c.server.isTrustedProxy(c.ip) {
+		return c.ip
+	}
+
+	if realIp := c.RequestHeader().Get(HeaderRealIp); realIp != "" {
+		return realIp
+	}
+
+	if forward := c.RequestHeader().Get(HeaderForward); forward != "" {
+		if ip := c.server.resolveForwardedFor(forward); ip != "" {
+			return ip
+		}
+	}
+
+	if forwarded := c.RequestHeader().Get(HeaderForwarded); forwarded != "" {
+		if ip := parseForwardedHeader(forwarded); ip != "" {
+			return ip
+		}
+	}
+
+	return c.ip
+}
+
+// resolveForwardedFor walks a comma-separated X-Forwarded-For value from
+// right (closest hop) to left (origin client), skipping hops that are
+// themselves trusted proxies, and returns the first untrusted address found.
+func (s *Server) resolveForwardedFor(header string) string {
+	parts := strings.Split(header, ",")
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		candidate := stripZoneAndBrackets(strings.TrimSpace(parts[i]))
+		if candidate == "" {
+			continue
+		}
+		if !s.isTrustedProxy(candidate) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// stripZoneAndBrackets normalises an address that may be bracketed IPv6
+// ("[::1]" or "[::1]:8080") or carry a port, returning the bare IP.
+func stripZoneAndBrackets(addr string) string {
+	if strings.HasPrefix(addr, "[") {
+		if end := strings.Index(addr, "]"); end != -1 {
+			return addr[1:end]
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+// parseForwardedHeader extracts the first `for=` parameter from an RFC 7239
+// Forwarded header, e.g. `Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43`.
+func parseForwardedHeader(header string) string {
+	for _, element := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			pair = strings.TrimSpace(pair)
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			return stripZoneAndBrackets(value)
+		}
+	}
+
+	return ""
+}
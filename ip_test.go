@@ -0,0 +1,128 @@
+package socketio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/collatzc/socketio/websocket"
+)
+
+func newTestChannel(t *testing.T, s *Server, peerIp string, headers http.Header) *Channel {
+	t.Helper()
+
+	c := &Channel{}
+	c.server = s
+	c.ip = peerIp
+	c.request = &http.Request{Header: headers}
+
+	return c
+}
+
+func TestIpNoTrustedProxiesIgnoresHeaders(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+
+	headers := http.Header{}
+	headers.Set(HeaderForward, "1.2.3.4")
+	c := newTestChannel(t, s, "10.0.0.1", headers)
+
+	if got := c.Ip(); got != "10.0.0.1" {
+		t.Fatalf("expected raw peer address, got %q", got)
+	}
+}
+
+func TestIpSpoofedForwardedForIsIgnored(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(HeaderForward, "1.2.3.4")
+	// peer is not a trusted proxy, so forwarding headers must be ignored
+	c := newTestChannel(t, s, "198.51.100.5", headers)
+
+	if got := c.Ip(); got != "198.51.100.5" {
+		t.Fatalf("expected raw peer address for untrusted peer, got %q", got)
+	}
+}
+
+func TestIpChainedTrustedProxiesWalksRightToLeft(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(HeaderForward, "198.51.100.5, 203.0.113.1, 203.0.113.2")
+	c := newTestChannel(t, s, "203.0.113.2", headers)
+
+	if got := c.Ip(); got != "198.51.100.5" {
+		t.Fatalf("expected first untrusted hop from the right, got %q", got)
+	}
+}
+
+func TestIpIPv6BracketSyntax(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(HeaderForward, "[2001:db8::1]:4000, 203.0.113.2")
+	c := newTestChannel(t, s, "203.0.113.2", headers)
+
+	if got := c.Ip(); got != "2001:db8::1" {
+		t.Fatalf("expected bracketed IPv6 address stripped of port, got %q", got)
+	}
+}
+
+func TestIpRealIpPrecedenceOverForwardedFor(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(HeaderRealIp, "192.0.2.60")
+	headers.Set(HeaderForward, "198.51.100.5, 203.0.113.2")
+	c := newTestChannel(t, s, "203.0.113.2", headers)
+
+	if got := c.Ip(); got != "192.0.2.60" {
+		t.Fatalf("expected X-Real-IP to take precedence, got %q", got)
+	}
+}
+
+func TestSetupEventLoopStripsPortFromRemoteAddr(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"198.51.100.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	// r.RemoteAddr is always "host:port" (bracketed for IPv6); SetupEventLoop
+	// must strip that down to a bare IP so isTrustedProxy and Ip() ever have a
+	// chance of matching, instead of comparing against the unparsable
+	// "host:port" string net.ParseIP always rejects.
+	c := s.SetupEventLoop(&websocket.Connection{}, "198.51.100.5:54321", &http.Request{Header: http.Header{}})
+
+	if c.ip != "198.51.100.5" {
+		t.Fatalf("expected SetupEventLoop to strip the port from RemoteAddr, got %q", c.ip)
+	}
+	if !s.isTrustedProxy(c.ip) {
+		t.Fatal("expected the stripped ip to match the configured trusted proxy range")
+	}
+}
+
+func TestIpForwardedHeaderFallback(t *testing.T) {
+	s := NewServer(websocket.Transport{})
+	if err := s.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(HeaderForwarded, `for=192.0.2.60;proto=http;by=203.0.113.43`)
+	c := newTestChannel(t, s, "203.0.113.2", headers)
+
+	if got := c.Ip(); got != "192.0.2.60" {
+		t.Fatalf("expected address parsed from Forwarded header, got %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+package socketio
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is the structured logging interface Server and Channel use
+// throughout this package. It mirrors zap's leveled, key/value style so that
+// a *zap.Logger (via NewZapLogger) can be passed straight through, while
+// still letting callers plug in their own implementation.
+type Logger interface {
+	Debug(msg string, fields ...zap.Field)
+	Info(msg string, fields ...zap.Field)
+	Warn(msg string, fields ...zap.Field)
+	Error(msg string, fields ...zap.Field)
+}
+
+// NewZapLogger wraps l so it satisfies Logger.
+func NewZapLogger(l *zap.Logger) Logger {
+	return &zapLogger{l}
+}
+
+type zapLogger struct {
+	l *zap.Logger
+}
+
+func (z *zapLogger) Debug(msg string, fields ...zap.Field) { z.l.Debug(msg, fields...) }
+func (z *zapLogger) Info(msg string, fields ...zap.Field)  { z.l.Info(msg, fields...) }
+func (z *zapLogger) Warn(msg string, fields ...zap.Field)  { z.l.Warn(msg, fields...) }
+func (z *zapLogger) Error(msg string, fields ...zap.Field) { z.l.Error(msg, fields...) }
+
+// nopLogger discards everything, used as the default so Server works without
+// any logging configuration, and in tests.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...zap.Field) {}
+func (nopLogger) Info(string, ...zap.Field)  {}
+func (nopLogger) Warn(string, ...zap.Field)  {}
+func (nopLogger) Error(string, ...zap.Field) {}
+
+// NewNopLogger returns a Logger that discards all log entries.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+// defaultLogger builds a zap production logger, except its level is lowered
+// to Debug when the DEBUG=1 environment variable is set, keeping this
+// package's long-standing DEBUG env var meaningful even though utils.Debug
+// is no longer called directly from the hot paths below.
+func defaultLogger() Logger {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if os.Getenv("DEBUG") == "1" {
+		level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = level
+
+	l, err := cfg.Build()
+	if err != nil {
+		return NewNopLogger()
+	}
+
+	return NewZapLogger(l)
+}
+
+// WithLogger configures the Logger used by Server and every Channel it
+// creates. Without this option, Server falls back to a production zap
+// logger whose level follows the DEBUG=1 environment variable.
+func WithLogger(l *zap.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = NewZapLogger(l)
+	}
+}
+
+// Logger returns the Channel's logger, pre-populated with sid, remote_ip,
+// protocol and (once the Channel connects to a namespace other than the
+// default one) namespace fields so log lines from concurrent connections can
+// be told apart. It falls back to the Server's base logger for a Channel
+// that SetupEventLoop did not create, and after its disconnect cleanup.
+func (c *Channel) Logger() Logger {
+	if c.server == nil {
+		return NewNopLogger()
+	}
+
+	c.server.channelLoggersLock.RLock()
+	l, ok := c.server.channelLoggers[c]
+	c.server.channelLoggersLock.RUnlock()
+	if ok {
+		return l
+	}
+
+	return c.server.logger
+}
+
+func newChannelLogger(s *Server, c *Channel) Logger {
+	cl := &channelLogger{
+		base: s.logger,
+		fields: []zap.Field{
+			zap.String("sid", c.Id()),
+			zap.String("remote_ip", c.ip),
+			zap.Any("protocol", c.conn.GetProtocol()),
+		},
+	}
+
+	s.channelLoggersLock.Lock()
+	s.channelLoggers[c] = cl
+	s.channelLoggersLock.Unlock()
+
+	return cl
+}
+
+// setChannelNamespace refines c's logger with a namespace field once a
+// CONNECT packet tells us which namespace it joined.
+func (s *Server) setChannelNamespace(c *Channel, nsp string) {
+	s.channelLoggersLock.Lock()
+	defer s.channelLoggersLock.Unlock()
+
+	if cl, ok := s.channelLoggers[c].(*channelLogger); ok {
+		s.channelLoggers[c] = cl.withNamespace(nsp)
+	}
+}
+
+func deleteChannelLogger(s *Server, c *Channel) {
+	s.channelLoggersLock.Lock()
+	delete(s.channelLoggers, c)
+	s.channelLoggersLock.Unlock()
+}
+
+// channelLogger prepends a fixed set of zap.Field identifying the owning
+// Channel to every call, so handlers never have to repeat them.
+type channelLogger struct {
+	base   Logger
+	fields []zap.Field
+}
+
+func (c *channelLogger) with(fields []zap.Field) []zap.Field {
+	return append(append([]zap.Field{}, c.fields...), fields...)
+}
+
+func (c *channelLogger) Debug(msg string, fields ...zap.Field) { c.base.Debug(msg, c.with(fields)...) }
+func (c *channelLogger) Info(msg string, fields ...zap.Field)  { c.base.Info(msg, c.with(fields)...) }
+func (c *channelLogger) Warn(msg string, fields ...zap.Field)  { c.base.Warn(msg, c.with(fields)...) }
+func (c *channelLogger) Error(msg string, fields ...zap.Field) { c.base.Error(msg, c.with(fields)...) }
+
+// withNamespace returns a copy of the channel logger with a namespace field
+// added, used once a CONNECT packet tells us which namespace a Channel
+// joined.
+func (c *channelLogger) withNamespace(nsp string) Logger {
+	return &channelLogger{
+		base:   c.base,
+		fields: append(append([]zap.Field{}, c.fields...), zap.String("namespace", nsp)),
+	}
+}
@@ -0,0 +1,101 @@
+package socketio
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/collatzc/socketio/metrics"
+)
+
+// WithMetrics registers this module's Prometheus collectors on reg and
+// instruments Server with them. Metrics are opt-in: without this option
+// importing the metrics package has no effect on the default registry.
+func WithMetrics(reg *prometheus.Registry) ServerOption {
+	return func(s *Server) {
+		s.metrics = metrics.New(reg)
+		s.metricsRegistry = reg
+	}
+}
+
+// MetricsHandler returns an http.Handler serving this Server's Prometheus
+// metrics, for callers to mount at e.g. /metrics. It is only useful once
+// WithMetrics has been configured; otherwise it serves an empty registry.
+func (s *Server) MetricsHandler() http.Handler {
+	if s.metricsRegistry == nil {
+		return promhttp.HandlerFor(prometheus.NewRegistry(), promhttp.HandlerOpts{})
+	}
+	return promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{})
+}
+
+func (s *Server) packetsLabels(nsp string) string {
+	if nsp == "" {
+		return "/"
+	}
+	return nsp
+}
+
+func (s *Server) metricConnectedSocketsInc(nsp string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.ConnectedSockets.WithLabelValues(s.packetsLabels(nsp)).Inc()
+}
+
+func (s *Server) metricConnectedSocketsDec(nsp string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.ConnectedSockets.WithLabelValues(s.packetsLabels(nsp)).Dec()
+}
+
+func (s *Server) metricRoomsSet(n int64) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.Rooms.Set(float64(n))
+}
+
+func (s *Server) metricBroadcastFanout(n int) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.BroadcastFanout.Observe(float64(n))
+}
+
+func (s *Server) metricAckLatencySeconds(seconds float64) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.AckLatency.Observe(seconds)
+}
+
+func (s *Server) metricPacketsSentInc(packetType, nsp string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.PacketsSent.WithLabelValues(packetType, s.packetsLabels(nsp)).Inc()
+}
+
+func (s *Server) metricPacketsReceivedInc(packetType, nsp string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.PacketsReceived.WithLabelValues(packetType, s.packetsLabels(nsp)).Inc()
+}
+
+func (s *Server) metricPingPongTimeoutInc() {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.PingPongTimeouts.Inc()
+}
+
+// RecordPingPongTimeout increments the ping_pong_timeouts_total counter. It
+// is the integration point for whatever closes a Channel after a scheduled
+// ping (see the SchedulePing call in onConnectStore) goes unanswered; call
+// it once, before closing the Channel.
+func (s *Server) RecordPingPongTimeout() {
+	s.metricPingPongTimeoutInc()
+}
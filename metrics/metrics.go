@@ -0,0 +1,80 @@
+// Package metrics registers the Prometheus collectors this module's Server
+// reports operational health through, once opted in with
+// socketio.WithMetrics.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collectors holds every metric Server instruments. socketio.WithMetrics
+// builds one with New on the registry it is given.
+type Collectors struct {
+	ConnectedSockets *prometheus.GaugeVec
+	Rooms            prometheus.Gauge
+	PacketsSent      *prometheus.CounterVec
+	PacketsReceived  *prometheus.CounterVec
+	BroadcastFanout  prometheus.Histogram
+	AckLatency       prometheus.Histogram
+	PingPongTimeouts prometheus.Counter
+	HandshakeErrors  prometheus.Counter
+}
+
+// New registers a fresh set of collectors on reg and returns them.
+func New(reg *prometheus.Registry) *Collectors {
+	c := &Collectors{
+		ConnectedSockets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "socketio",
+			Name:      "connected_sockets",
+			Help:      "Number of currently connected sockets.",
+		}, []string{"namespace"}),
+		Rooms: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "socketio",
+			Name:      "rooms",
+			Help:      "Number of rooms with at least one member.",
+		}),
+		PacketsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "socketio",
+			Name:      "packets_sent_total",
+			Help:      "Packets sent to clients.",
+		}, []string{"type", "namespace"}),
+		PacketsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "socketio",
+			Name:      "packets_received_total",
+			Help:      "Packets received from clients.",
+		}, []string{"type", "namespace"}),
+		BroadcastFanout: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "socketio",
+			Name:      "broadcast_fanout_size",
+			Help:      "Number of sockets a single broadcast was delivered to.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		AckLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "socketio",
+			Name:      "ack_latency_seconds",
+			Help:      "Round-trip time between emitting an event with an ack and receiving it.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		PingPongTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "socketio",
+			Name:      "ping_pong_timeouts_total",
+			Help:      "Connections closed because a ping/pong was not answered in time.",
+		}),
+		HandshakeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "socketio",
+			Name:      "handshake_errors_total",
+			Help:      "Handshakes rejected before the WebSocket upgrade completed.",
+		}),
+	}
+
+	reg.MustRegister(
+		c.ConnectedSockets,
+		c.Rooms,
+		c.PacketsSent,
+		c.PacketsReceived,
+		c.BroadcastFanout,
+		c.AckLatency,
+		c.PingPongTimeouts,
+		c.HandshakeErrors,
+	)
+
+	return c
+}
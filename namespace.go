@@ -0,0 +1,329 @@
+package socketio
+
+import (
+	"sync"
+
+	"github.com/collatzc/socketio/protocol"
+)
+
+// Namespace scopes event handlers, rooms and connected sids to a single
+// Socket.IO namespace (the Nsp field on protocol.MsgPack / protocol.Message),
+// so that e.g. "/chat" and "/admin" can be handled independently without
+// sharing rooms. Obtain one with Server.Of.
+//
+// The default namespace ("/") is a thin wrapper around the Server itself, so
+// Server.On/Join/Leave/BroadcastTo/List keep operating exactly as they did
+// before namespaces were introduced.
+type Namespace struct {
+	methods
+
+	server *Server
+	name   string
+
+	channels     map[string]map[*Channel]struct{}
+	rooms        map[*Channel]map[string]struct{}
+	channelsLock sync.RWMutex
+
+	sids     map[string]*Channel
+	sidsLock sync.RWMutex
+}
+
+func (ns *Namespace) isDefault() bool {
+	return ns.name == protocol.DefaultNsp
+}
+
+// Of returns the Namespace scoped to nsp, creating it on first use. An empty
+// nsp is treated as protocol.DefaultNsp.
+func (s *Server) Of(nsp string) *Namespace {
+	if nsp == "" {
+		nsp = protocol.DefaultNsp
+	}
+
+	s.namespacesLock.RLock()
+	ns, ok := s.namespaces[nsp]
+	s.namespacesLock.RUnlock()
+	if ok {
+		return ns
+	}
+
+	s.namespacesLock.Lock()
+	defer s.namespacesLock.Unlock()
+
+	if ns, ok = s.namespaces[nsp]; ok {
+		return ns
+	}
+
+	ns = &Namespace{
+		server: s,
+		name:   nsp,
+	}
+	if nsp != protocol.DefaultNsp {
+		ns.channels = make(map[string]map[*Channel]struct{})
+		ns.rooms = make(map[*Channel]map[string]struct{})
+		ns.sids = make(map[string]*Channel)
+	}
+
+	s.namespaces[nsp] = ns
+
+	return ns
+}
+
+// Name returns the namespace's path, e.g. "/chat".
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+// On registers a handler for method within this namespace.
+func (ns *Namespace) On(method string, f interface{}) error {
+	if ns.isDefault() {
+		return ns.server.On(method, f)
+	}
+	return ns.methods.On(method, f)
+}
+
+// OnConnection sets the callback invoked when a Channel connects to this
+// namespace.
+func (ns *Namespace) OnConnection(f func(c *Channel)) {
+	if ns.isDefault() {
+		ns.server.onConnection = f
+		return
+	}
+	ns.onConnection = f
+}
+
+// OnDisconnection sets the callback invoked when a Channel connected to this
+// namespace disconnects.
+func (ns *Namespace) OnDisconnection(f func(c *Channel)) {
+	if ns.isDefault() {
+		ns.server.onDisconnection = f
+		return
+	}
+	ns.onDisconnection = f
+}
+
+// adapterRoomKey returns the key this namespace uses to talk to the
+// Server's Adapter about room, so that e.g. "lobby" in "/chat" doesn't
+// collide with "lobby" in the default namespace or in any other namespace.
+func (ns *Namespace) adapterRoomKey(room string) string {
+	if ns.isDefault() {
+		return room
+	}
+	return ns.name + ":" + room
+}
+
+// Join adds c to room within this namespace.
+func (ns *Namespace) Join(c *Channel, room string) error {
+	if ns.isDefault() {
+		return c.Join(room)
+	}
+
+	ns.channelsLock.Lock()
+	_, alreadyLocal := ns.channels[room]
+	if !alreadyLocal {
+		ns.channels[room] = make(map[*Channel]struct{})
+	}
+	if _, ok := ns.rooms[c]; !ok {
+		ns.rooms[c] = make(map[string]struct{})
+	}
+
+	ns.channels[room][c] = struct{}{}
+	ns.rooms[c][room] = struct{}{}
+	ns.channelsLock.Unlock()
+
+	if !alreadyLocal {
+		ns.subscribeRoom(room)
+	}
+
+	return ns.server.adapter.AddSocketToRoom(ns.adapterRoomKey(room), c.Id())
+}
+
+// Leave removes c from room within this namespace.
+func (ns *Namespace) Leave(c *Channel, room string) error {
+	if ns.isDefault() {
+		return c.Leave(room)
+	}
+
+	ns.channelsLock.Lock()
+	if _, ok := ns.channels[room]; ok {
+		delete(ns.channels[room], c)
+		if len(ns.channels[room]) == 0 {
+			delete(ns.channels, room)
+		}
+	}
+	if _, ok := ns.rooms[c]; ok {
+		delete(ns.rooms[c], room)
+	}
+	ns.channelsLock.Unlock()
+
+	return ns.server.adapter.RemoveSocketFromRoom(ns.adapterRoomKey(room), c.Id())
+}
+
+// subscribeRoom asks the adapter to start delivering remote broadcasts for
+// room in this namespace to this node, the first time it sees that room
+// locally.
+func (ns *Namespace) subscribeRoom(room string) {
+	_ = ns.server.adapter.Subscribe(ns.adapterRoomKey(room), func(event string, data interface{}, origin string) {
+		ns.deliverLocally(room, event, data, origin)
+	})
+}
+
+// deliverLocally re-emits an event published by another node to every alive
+// channel this node has in room within this namespace, skipping the sid that
+// originated it.
+func (ns *Namespace) deliverLocally(room, event string, data interface{}, origin string) {
+	ns.channelsLock.RLock()
+	roomChannels := ns.channels[room]
+	targets := make([]*Channel, 0, len(roomChannels))
+	for cn := range roomChannels {
+		if cn.Id() != origin && cn.IsAlive() {
+			targets = append(targets, cn)
+		}
+	}
+	ns.channelsLock.RUnlock()
+
+	for _, cn := range targets {
+		go cn.Emit(event, data)
+	}
+}
+
+// List returns the channels that have joined room within this namespace.
+func (ns *Namespace) List(room string) []*Channel {
+	if ns.isDefault() {
+		return ns.server.List(room)
+	}
+
+	ns.channelsLock.RLock()
+	defer ns.channelsLock.RUnlock()
+
+	roomChannels, ok := ns.channels[room]
+	if !ok {
+		return []*Channel{}
+	}
+
+	out := make([]*Channel, 0, len(roomChannels))
+	for cn := range roomChannels {
+		out = append(out, cn)
+	}
+
+	return out
+}
+
+// BroadcastTo emits method to every alive channel in room within this
+// namespace.
+func (ns *Namespace) BroadcastTo(room, method string, args interface{}) {
+	if ns.isDefault() {
+		ns.server.BroadcastTo(room, method, args)
+		return
+	}
+
+	ns.channelsLock.RLock()
+	fanout := 0
+	for cn := range ns.channels[room] {
+		if cn.IsAlive() {
+			go cn.Emit(method, args)
+			fanout++
+		}
+	}
+	ns.channelsLock.RUnlock()
+	ns.server.metricBroadcastFanout(fanout)
+
+	_ = ns.server.adapter.Publish(ns.adapterRoomKey(room), method, args, "")
+}
+
+// Emit broadcasts method to every channel connected to this namespace.
+func (ns *Namespace) Emit(method string, args interface{}) {
+	if ns.isDefault() {
+		ns.server.BroadcastToAll(method, args)
+		return
+	}
+
+	ns.sidsLock.RLock()
+	defer ns.sidsLock.RUnlock()
+
+	for _, cn := range ns.sids {
+		if cn.IsAlive() {
+			go cn.Emit(method, args)
+		}
+	}
+}
+
+// addChannel registers c as connected to this namespace and fires its
+// OnConnection callback. It is called once per namespace the client
+// requests, either during the initial open sequence or when a later CONNECT
+// packet names an additional namespace. A Channel already registered for ns
+// (e.g. SendOpenSequence completed "/" and a client CONNECT for "/" arrives
+// right after) is a no-op, so the connected-sockets metric and OnConnection
+// only ever fire once per (Channel, Namespace) pair. OnAuth and the CONNECT
+// ack are run by connectNamespace before this check, so they run again for
+// a repeated CONNECT.
+func (ns *Namespace) addChannel(c *Channel) {
+	c.server.namespacesLock.Lock()
+	if _, ok := c.server.channelNsps[c]; !ok {
+		c.server.channelNsps[c] = make(map[string]struct{})
+	}
+	if _, already := c.server.channelNsps[c][ns.name]; already {
+		c.server.namespacesLock.Unlock()
+		return
+	}
+	c.server.channelNsps[c][ns.name] = struct{}{}
+	c.server.namespacesLock.Unlock()
+
+	if !ns.isDefault() {
+		ns.sidsLock.Lock()
+		ns.sids[c.Id()] = c
+		ns.sidsLock.Unlock()
+
+		c.server.setChannelNamespace(c, ns.name)
+	}
+
+	ns.server.metricConnectedSocketsInc(ns.name)
+
+	// OnConnection for the default namespace is registered on the Server
+	// itself (see Namespace.OnConnection), so dispatch it through the
+	// Server's own methods rather than this Namespace's, which for "/" are
+	// never populated.
+	if ns.isDefault() {
+		ns.server.callLoopEvent(c, OnConnection)
+		return
+	}
+	ns.callLoopEvent(c, OnConnection)
+}
+
+// removeChannel unregisters c from this namespace and, for any namespace but
+// the default one, fires its OnDisconnection callback.
+func (ns *Namespace) removeChannel(c *Channel) {
+	if !ns.isDefault() {
+		ns.sidsLock.Lock()
+		delete(ns.sids, c.Id())
+		ns.sidsLock.Unlock()
+	}
+
+	ns.server.metricConnectedSocketsDec(ns.name)
+
+	// The default namespace's OnDisconnection handler is the Server's own
+	// onDisconnection (onDisconnectCleanup, see NewServer), and that is the
+	// only caller for "/"; dispatching it again here would re-enter it.
+	if ns.isDefault() {
+		return
+	}
+	ns.callLoopEvent(c, OnDisconnection)
+}
+
+// removeChannelFromNamespaces cleans up c's membership in every non-default
+// namespace it had connected to, called on disconnect.
+func (s *Server) removeChannelFromNamespaces(c *Channel) {
+	s.namespacesLock.Lock()
+	nsps := s.channelNsps[c]
+	delete(s.channelNsps, c)
+	s.namespacesLock.Unlock()
+
+	for nsp := range nsps {
+		if nsp == protocol.DefaultNsp {
+			// the default namespace's disconnect bookkeeping is handled by
+			// the explicit Of(protocol.DefaultNsp).removeChannel call this
+			// function's only caller also makes (see server.go)
+			continue
+		}
+		s.Of(nsp).removeChannel(c)
+	}
+}
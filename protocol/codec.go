@@ -0,0 +1,142 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/collatzc/socketio/utils"
+)
+
+// Codec converts between wire bytes and Message. Server.SendOpenSequence
+// advertises the Server's configured codec's Name() in the OPEN payload.
+// ContentType() reports the WebSocket frame type the encoding needs
+// (gorilla/websocket's TextMessage or BinaryMessage); the socketio Server
+// does not read it when writing frames.
+type Codec interface {
+	Marshal(Message) ([]byte, error)
+	Unmarshal([]byte) (Message, error)
+	ContentType() int
+	Name() string
+}
+
+// JSONCodec is this module's original wire format, kept as the default so
+// existing clients and servers are unaffected.
+type JSONCodec struct{}
+
+func NewJSONCodec() Codec { return JSONCodec{} }
+
+func (JSONCodec) Name() string      { return "json" }
+func (JSONCodec) ContentType() int  { return gorilla.TextMessage }
+func (JSONCodec) Marshal(m Message) ([]byte, error) {
+	return utils.Json.Marshal(m)
+}
+func (JSONCodec) Unmarshal(data []byte) (Message, error) {
+	var m Message
+	err := utils.Json.Unmarshal(data, &m)
+	return m, err
+}
+
+// MessagePackCodec encodes Message as MessagePack using the
+// github.com/ugorji/go/codec library, trading JSON's readability for a
+// smaller, allocation-lighter wire payload.
+type MessagePackCodec struct{}
+
+func NewMessagePackCodec() Codec { return MessagePackCodec{} }
+
+func (MessagePackCodec) Name() string     { return "msgpack" }
+func (MessagePackCodec) ContentType() int { return gorilla.BinaryMessage }
+
+func (MessagePackCodec) Marshal(m Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf, &codec.MsgpackHandle{})
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MessagePackCodec) Unmarshal(data []byte) (Message, error) {
+	var m Message
+	dec := codec.NewDecoderBytes(data, &codec.MsgpackHandle{})
+	err := dec.Decode(&m)
+	return m, err
+}
+
+// protobufEnvelope carries a Message's routing fields; Body holds the
+// protobuf-encoded event argument, looked up per event name via
+// ProtobufCodec.RegisterType.
+type protobufEnvelope struct {
+	Type   int    `json:"type"`
+	Method string `json:"method"`
+	Nsp    string `json:"nsp"`
+	AckId  int    `json:"id"`
+	Body   []byte `json:"body,omitempty"`
+}
+
+// ProtobufCodec encodes a Message's first argument as a proto.Message and
+// decodes it into the type registered for the event name. Marshal fails when
+// that argument isn't a proto.Message; it does not check for a registered
+// type. Unmarshal fails for an event that carries an argument but has no
+// registered type.
+type ProtobufCodec struct {
+	types map[string]func() proto.Message
+}
+
+func NewProtobufCodec() *ProtobufCodec {
+	return &ProtobufCodec{types: make(map[string]func() proto.Message)}
+}
+
+// RegisterType associates event with a constructor for the proto.Message its
+// single argument should be encoded/decoded as.
+func (c *ProtobufCodec) RegisterType(event string, newMessage func() proto.Message) {
+	c.types[event] = newMessage
+}
+
+func (c *ProtobufCodec) Name() string     { return "protobuf" }
+func (c *ProtobufCodec) ContentType() int { return gorilla.BinaryMessage }
+
+func (c *ProtobufCodec) Marshal(m Message) ([]byte, error) {
+	env := protobufEnvelope{Type: m.Type, Method: m.Method, Nsp: m.Nsp, AckId: m.AckId}
+
+	if len(m.Args) > 0 {
+		pm, ok := m.Args[0].(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("protobuf codec: event %q argument is not a proto.Message", m.Method)
+		}
+		body, err := proto.Marshal(pm)
+		if err != nil {
+			return nil, err
+		}
+		env.Body = body
+	}
+
+	return utils.Json.Marshal(env)
+}
+
+func (c *ProtobufCodec) Unmarshal(data []byte) (Message, error) {
+	var env protobufEnvelope
+	if err := utils.Json.Unmarshal(data, &env); err != nil {
+		return Message{}, err
+	}
+
+	m := Message{Type: env.Type, Method: env.Method, Nsp: env.Nsp, AckId: env.AckId}
+
+	if len(env.Body) > 0 {
+		newMessage, ok := c.types[env.Method]
+		if !ok {
+			return Message{}, fmt.Errorf("protobuf codec: no type registered for event %q", env.Method)
+		}
+
+		pm := newMessage()
+		if err := proto.Unmarshal(env.Body, pm); err != nil {
+			return Message{}, err
+		}
+		m.Args = []interface{}{pm}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package protocol_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/collatzc/socketio/protocol"
+)
+
+// chatMessage is a representative event payload: a handful of scalar fields,
+// similar in shape to a typical "chat message" or "cursor move" event.
+type chatMessage struct {
+	Room string `json:"room"`
+	User string `json:"user"`
+	Text string `json:"text"`
+}
+
+func benchmarkCodec(b *testing.B, c protocol.Codec) {
+	msg := protocol.Message{
+		Type:   protocol.EVENT,
+		Method: "chat message",
+		Nsp:    protocol.DefaultNsp,
+		Args:   []interface{}{chatMessage{Room: "lobby", User: "alice", Text: "hello, world"}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := c.Marshal(msg)
+		if err != nil {
+			b.Fatalf("marshal: %v", err)
+		}
+		if _, err := c.Unmarshal(data); err != nil {
+			b.Fatalf("unmarshal: %v", err)
+		}
+	}
+}
+
+func BenchmarkJSONCodec(b *testing.B) {
+	benchmarkCodec(b, protocol.NewJSONCodec())
+}
+
+func BenchmarkMessagePackCodec(b *testing.B) {
+	benchmarkCodec(b, protocol.NewMessagePackCodec())
+}
+
+// BenchmarkProtobufCodec mirrors the other codec benchmarks above, but with
+// a proto.Message argument since ProtobufCodec can't encode chatMessage.
+func BenchmarkProtobufCodec(b *testing.B) {
+	codec := protocol.NewProtobufCodec()
+	codec.RegisterType("chat message", func() proto.Message { return &wrapperspb.StringValue{} })
+
+	msg := protocol.Message{
+		Type:   protocol.EVENT,
+		Method: "chat message",
+		Nsp:    protocol.DefaultNsp,
+		Args:   []interface{}{&wrapperspb.StringValue{Value: "hello, world"}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := codec.Marshal(msg)
+		if err != nil {
+			b.Fatalf("marshal: %v", err)
+		}
+		if _, err := codec.Unmarshal(data); err != nil {
+			b.Fatalf("unmarshal: %v", err)
+		}
+	}
+}
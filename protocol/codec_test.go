@@ -0,0 +1,116 @@
+package protocol_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/collatzc/socketio/protocol"
+)
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	codec := protocol.NewJSONCodec()
+
+	msg := protocol.Message{Type: protocol.EVENT, Method: "ping", Nsp: protocol.DefaultNsp}
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Method != msg.Method || got.Nsp != msg.Nsp {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, msg)
+	}
+}
+
+func TestProtobufCodecRoundTrip(t *testing.T) {
+	codec := protocol.NewProtobufCodec()
+	codec.RegisterType("chat message", func() proto.Message { return &wrapperspb.StringValue{} })
+
+	msg := protocol.Message{
+		Type:   protocol.EVENT,
+		Method: "chat message",
+		Nsp:    protocol.DefaultNsp,
+		Args:   []interface{}{&wrapperspb.StringValue{Value: "hello, world"}},
+	}
+
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Method != msg.Method || got.Nsp != msg.Nsp {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, msg)
+	}
+	if len(got.Args) != 1 {
+		t.Fatalf("expected 1 arg, got %d", len(got.Args))
+	}
+	sv, ok := got.Args[0].(*wrapperspb.StringValue)
+	if !ok || sv.Value != "hello, world" {
+		t.Fatalf("expected decoded StringValue %q, got %#v", "hello, world", got.Args[0])
+	}
+}
+
+func TestProtobufCodecMarshalRejectsNonProtoArgument(t *testing.T) {
+	codec := protocol.NewProtobufCodec()
+	codec.RegisterType("chat message", func() proto.Message { return &wrapperspb.StringValue{} })
+
+	msg := protocol.Message{
+		Type:   protocol.EVENT,
+		Method: "chat message",
+		Nsp:    protocol.DefaultNsp,
+		Args:   []interface{}{"hello, world"},
+	}
+
+	if _, err := codec.Marshal(msg); err == nil {
+		t.Fatal("expected an error marshaling a non-proto.Message argument")
+	}
+}
+
+func TestProtobufCodecUnmarshalRejectsUnregisteredEvent(t *testing.T) {
+	encoder := protocol.NewProtobufCodec()
+	encoder.RegisterType("chat message", func() proto.Message { return &wrapperspb.StringValue{} })
+
+	msg := protocol.Message{
+		Type:   protocol.EVENT,
+		Method: "chat message",
+		Nsp:    protocol.DefaultNsp,
+		Args:   []interface{}{&wrapperspb.StringValue{Value: "hello, world"}},
+	}
+
+	data, err := encoder.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	decoder := protocol.NewProtobufCodec()
+	if _, err := decoder.Unmarshal(data); err == nil {
+		t.Fatal("expected an error unmarshaling an event with no registered type")
+	}
+}
+
+func TestMessagePackCodecRoundTrip(t *testing.T) {
+	codec := protocol.NewMessagePackCodec()
+
+	msg := protocol.Message{Type: protocol.EVENT, Method: "ping", Nsp: protocol.DefaultNsp}
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Method != msg.Method || got.Nsp != msg.Nsp {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, msg)
+	}
+}
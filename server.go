@@ -7,10 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/collatzc/socketio/metrics"
 	"github.com/collatzc/socketio/protocol"
 	"github.com/collatzc/socketio/utils"
 	"github.com/collatzc/socketio/websocket"
@@ -38,20 +43,52 @@ type Server struct {
 	sidsLock sync.RWMutex
 
 	tr websocket.Transport
+
+	adapter Adapter
+
+	namespaces     map[string]*Namespace
+	channelNsps    map[*Channel]map[string]struct{}
+	namespacesLock sync.RWMutex
+
+	trustedProxies     []*net.IPNet
+	trustedProxiesLock sync.RWMutex
+
+	logger             Logger
+	channelLoggers     map[*Channel]Logger
+	channelLoggersLock sync.RWMutex
+
+	metrics         *metrics.Collectors
+	metricsRegistry *prometheus.Registry
+
+	middlewares     []Middleware
+	middlewaresLock sync.RWMutex
+
+	onAuth     func(c *Channel, auth map[string]any) error
+	onAuthLock sync.RWMutex
+
+	channelData     map[*Channel]any
+	channelDataLock sync.RWMutex
+
+	codec protocol.Codec
 }
 
-func (c *Channel) Close() {
-	if c.server != nil {
-		closeChannel(c, &c.server.methods)
+// ServerOption configures optional behaviour on a Server at construction
+// time, see NewServer.
+type ServerOption func(*Server)
+
+// WithAdapter replaces the default in-memory Adapter with one that can fan
+// room membership and broadcasts out across a cluster of nodes, such as the
+// Redis- or NATS-backed adapters in this module's adapter subpackages.
+func WithAdapter(a Adapter) ServerOption {
+	return func(s *Server) {
+		s.adapter = a
 	}
 }
 
-func (c *Channel) Ip() string {
-	forward := c.RequestHeader().Get(HeaderForward)
-	if forward != "" {
-		return forward
+func (c *Channel) Close() {
+	if c.server != nil {
+		closeChannel(c, &c.server.methods)
 	}
-	return c.ip
 }
 
 func (c *Channel) RequestHeader() http.Header {
@@ -83,7 +120,8 @@ func (c *Channel) Join(room string) error {
 	defer c.server.channelsLock.Unlock()
 
 	cn := c.server.channels
-	if _, ok := cn[room]; !ok {
+	_, alreadyLocal := cn[room]
+	if !alreadyLocal {
 		cn[room] = make(map[*Channel]struct{})
 	}
 
@@ -95,6 +133,14 @@ func (c *Channel) Join(room string) error {
 	cn[room][c] = struct{}{}
 	byRoom[c][room] = struct{}{}
 
+	if !alreadyLocal {
+		c.server.subscribeRoom(room)
+	}
+	_ = c.server.adapter.AddSocketToRoom(room, c.Id())
+
+	c.server.metricRoomsSet(int64(len(cn)))
+	c.Logger().Debug("joined room", zap.String("room", room))
+
 	return nil
 }
 
@@ -119,6 +165,11 @@ func (c *Channel) Leave(room string) error {
 		delete(byRoom[c], room)
 	}
 
+	_ = c.server.adapter.RemoveSocketFromRoom(room, c.Id())
+
+	c.server.metricRoomsSet(int64(len(cn)))
+	c.Logger().Debug("left room", zap.String("room", room))
+
 	return nil
 }
 
@@ -178,38 +229,47 @@ func (c *Channel) BroadcastTo(room, method string, args interface{}) {
 		return
 	}
 
+	fanout := 0
 	for cn := range roomChannels {
 		if cn.Id() != c.Id() && cn.IsAlive() {
 			go cn.Emit(method, args)
+			fanout++
 		}
 	}
+	c.server.metricBroadcastFanout(fanout)
+
+	_ = c.server.adapter.Publish(room, method, args, c.Id())
 }
 
 func (s *Server) BroadcastTo(room, method string, args interface{}) {
+	fanout := 0
 	s.channelsLock.RLock()
-	defer s.channelsLock.RUnlock()
-
 	roomChannels, ok := s.channels[room]
-	if !ok {
-		return
-	}
-
-	for cn := range roomChannels {
-		if cn.IsAlive() {
-			go cn.Emit(method, args)
+	if ok {
+		for cn := range roomChannels {
+			if cn.IsAlive() {
+				go cn.Emit(method, args)
+				fanout++
+			}
 		}
 	}
+	s.channelsLock.RUnlock()
+	s.metricBroadcastFanout(fanout)
+
+	_ = s.adapter.Publish(room, method, args, "")
 }
 
 func (s *Server) BroadcastToAll(method string, args interface{}) {
+	fanout := 0
 	s.sidsLock.RLock()
-	defer s.sidsLock.RUnlock()
-
 	for _, cn := range s.sids {
 		if cn.IsAlive() {
 			go cn.Emit(method, args)
+			fanout++
 		}
 	}
+	s.sidsLock.RUnlock()
+	s.metricBroadcastFanout(fanout)
 }
 
 func generateNewId(custom string) string {
@@ -232,6 +292,8 @@ func onConnectStore(c *Channel) {
 		// in protocol v4, the server sends a ping, and the client answers with a pong
 		go SchedulePing(c)
 	}
+
+	c.Logger().Debug("sid registered")
 }
 
 func onDisconnectCleanup(c *Channel) {
@@ -248,12 +310,26 @@ func onDisconnectCleanup(c *Channel) {
 					delete(cn, room)
 				}
 			}
+			_ = c.server.adapter.RemoveSocketFromRoom(room, c.Id())
 		}
 
 		delete(c.server.rooms, c)
 	}
 
+	c.server.metricRoomsSet(int64(len(cn)))
+
+	c.server.removeChannelFromNamespaces(c)
+
+	// removeChannelFromNamespaces only walks the non-default namespaces c
+	// joined (see Namespace.addChannel), so the default namespace's own
+	// bookkeeping happens here instead. Its OnDisconnection dispatch is the
+	// one already running this function, so removeChannel does not repeat it.
+	c.server.Of(protocol.DefaultNsp).removeChannel(c)
+
+	c.Logger().Info("channel disconnected")
 	go deleteSid(c)
+	deleteChannelLogger(c.server, c)
+	c.server.deleteChannelData(c)
 }
 
 func deleteSid(c *Channel) {
@@ -263,39 +339,75 @@ func deleteSid(c *Channel) {
 	delete(c.server.sids, c.Id())
 }
 
-func (s *Server) SendOpenSequence(c *Channel) {
-	jsonHdr, err := utils.Json.Marshal(&c.header)
+// SendOpenSequence sends the engine.io OPEN packet followed by a Socket.IO
+// CONNECT packet for each namespace in nsps. When nsps is empty it connects
+// only protocol.DefaultNsp, matching this package's historical behaviour.
+func (s *Server) SendOpenSequence(c *Channel, nsps ...string) {
+	openPayload := struct {
+		Header
+		Codecs []string `json:"codecs,omitempty"`
+	}{
+		Header: c.header,
+		Codecs: []string{s.codec.Name()},
+	}
+
+	jsonHdr, err := utils.Json.Marshal(&openPayload)
 	if err != nil {
 		panic(err)
 	}
 
 	c.out <- protocol.OpenMsg + string(jsonHdr)
+	s.metricPacketsSentInc("open", protocol.DefaultNsp)
+
+	if len(nsps) == 0 {
+		nsps = []string{protocol.DefaultNsp}
+	}
+
+	for _, nsp := range nsps {
+		// The initial open sequence has no client-supplied auth payload yet
+		// (that arrives on a client CONNECT packet handled by
+		// ProcessConnectPacket); passing nil still runs OnAuth so a
+		// configured callback can reject connections outright.
+		s.connectNamespace(c, nsp, nil)
+	}
+}
+
+func (s *Server) sendConnect(c *Channel, nsp string) {
+	defer s.metricPacketsSentInc("connect", nsp)
 
 	if s.tr.BinaryMessage {
 		// in protocol v4 & binary msg ps: {"type":0,"data":{"sid":"HWEr440000:1:R1CHyink:shadiao:101"},"nsp":"/","id":0}
 		c.out <- &protocol.MsgPack{
 			Type: protocol.CONNECT,
-			Nsp:  protocol.DefaultNsp,
+			Nsp:  nsp,
 			Data: struct {
 				Sid string `json:"sid"`
 			}{Sid: c.Id()},
 		}
-	} else {
-		marshal, err := utils.Json.Marshal(&struct {
-			Sid string `json:"sid"`
-		}{
-			Sid: c.Id(),
-		})
-		if err != nil {
-			panic(err)
-		}
+		return
+	}
+
+	marshal, err := utils.Json.Marshal(&struct {
+		Sid string `json:"sid"`
+	}{
+		Sid: c.Id(),
+	})
+	if err != nil {
+		panic(err)
+	}
 
+	if nsp == protocol.DefaultNsp {
 		c.out <- protocol.CommonMsg + protocol.OpenMsg + string(marshal)
+		return
 	}
+
+	// namespaced CONNECT over the text transport is the "40<nsp>,<payload>"
+	// form, e.g. "40/chat,{\"sid\":\"...\"}"
+	c.out <- protocol.CommonMsg + protocol.OpenMsg + nsp + "," + string(marshal)
 }
 
 func (s *Server) SetupEventLoop(conn *websocket.Connection, remoteAddr string,
-	r *http.Request) {
+	r *http.Request) *Channel {
 
 	interval, timeout := conn.PingParams()
 	hdr := Header{
@@ -307,19 +419,23 @@ func (s *Server) SetupEventLoop(conn *websocket.Connection, remoteAddr string,
 
 	c := &Channel{}
 	c.conn = conn
-	c.ip = remoteAddr
+	c.ip = stripZoneAndBrackets(remoteAddr)
 	c.request = r
 	c.initChannel()
 
 	c.server = s
 	c.header = hdr
 
+	clog := newChannelLogger(s, c)
+	clog.Info("channel connected", zap.String("transport", "websocket"))
+	c.ack.setLogger(clog, s)
+
 	s.SendOpenSequence(c)
 
 	go inLoop(c, &s.methods)
 	go outLoop(c, &s.methods)
 
-	s.callLoopEvent(c, OnConnection)
+	return c
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -327,12 +443,23 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(key, el)
 	}
 
+	ctxData, err := s.runMiddlewares(r)
+	if err != nil {
+		s.logger.Warn("handshake rejected by middleware", zap.Error(err))
+		if s.metrics != nil {
+			s.metrics.HandshakeErrors.Inc()
+		}
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := s.tr.HandleConnection(w, r)
 	if err != nil {
 		return
 	}
 
-	s.SetupEventLoop(conn, r.RemoteAddr, r)
+	c := s.SetupEventLoop(conn, r.RemoteAddr, r)
+	s.setChannelData(c, ctxData)
 	s.tr.Serve(w, r)
 }
 
@@ -350,6 +477,27 @@ func (s *Server) AmountOfRooms() int64 {
 	return int64(len(s.channels))
 }
 
+// RoomMembers returns the sids that have joined room across the whole
+// cluster, as reported by the configured Adapter. With the default
+// MemoryAdapter this is equivalent to List(room) mapped to sids.
+func (s *Server) RoomMembers(room string) ([]string, error) {
+	return s.adapter.RoomMembers(room)
+}
+
+// ClusterAmount returns the number of sids that have joined room across the
+// whole cluster, the cluster-aware counterpart to Amount (which only counts
+// sockets connected to this process). It is the opt-in cluster-wide count
+// promised for room membership; there is no cluster-wide equivalent for
+// AmountOfSids, since the Adapter interface tracks membership per room, not
+// a total session count across the cluster.
+func (s *Server) ClusterAmount(room string) (int, error) {
+	members, err := s.adapter.RoomMembers(room)
+	if err != nil {
+		return 0, err
+	}
+	return len(members), nil
+}
+
 func (s *Server) EnableCORS(domain string) {
 	s.headers["Access-Control-Allow-Origin"] = domain
 	s.headers["Access-Control-Allow-Credentials"] = "true"
@@ -363,15 +511,26 @@ func (s *Server) UpdateTransport(tr websocket.Transport) {
 	s.tr = tr
 }
 
-func NewServer(tr websocket.Transport) *Server {
+func NewServer(tr websocket.Transport, opts ...ServerOption) *Server {
 	s := Server{}
 	s.tr = tr
 	s.headers = make(map[string]string)
 	s.channels = make(map[string]map[*Channel]struct{})
 	s.rooms = make(map[*Channel]map[string]struct{})
 	s.sids = make(map[string]*Channel)
+	s.adapter = NewMemoryAdapter()
+	s.namespaces = make(map[string]*Namespace)
+	s.channelNsps = make(map[*Channel]map[string]struct{})
+	s.logger = defaultLogger()
+	s.channelLoggers = make(map[*Channel]Logger)
+	s.channelData = make(map[*Channel]any)
+	s.codec = protocol.NewJSONCodec()
 	s.onConnection = onConnectStore
 	s.onDisconnection = onDisconnectCleanup
 
+	for _, opt := range opts {
+		opt(&s)
+	}
+
 	return &s
 }